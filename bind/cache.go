@@ -0,0 +1,281 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"go/doc"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"golang.org/x/tools/go/types"
+)
+
+// docCache memoizes the (parent, name) -> doc-string lookups performed
+// by Package.getDoc, keyed by a fingerprint of the type-checked
+// package's exported API.
+//
+// Note this only caches the *derived* doc strings, not the Struct/Func/
+// Const/Var objects themselves: those embed live *types.Object and
+// *types.Type values handed to us by the type-checker for this run, and
+// can't be serialized and reused across processes. What getDoc does is
+// expensive independently of that, though: it walks
+// p.doc.Consts/Vars/Types/Funcs linearly for every exported object,
+// which makes Package.process effectively O(n^2) on packages with many
+// exported symbols. Memoizing that walk on disk turns a repeat `gopy
+// bind` over an unchanged package into O(1) map lookups instead.
+//
+// See stubCache below for the cross-run skip of Package.process
+// itself, over the one artifact in this tree whose full shape really
+// is just a serializable string projection of those objects.
+type docCache struct {
+	Fingerprint string
+	Docs        map[string]string // "parent\x00name" -> doc string
+}
+
+// stubCache persists the rendered .pyi bytes GenPyStub produced for a
+// package, keyed by the same fingerprint as docCache. GenPyStubCached
+// checks this before calling NewPackage at all: on a hit, it replays
+// the cached bytes and skips Package.process's full scope walk and
+// Struct/Func/Const/Var rebuild entirely, which is the cross-run
+// "don't re-process a package whose exported API and docs haven't
+// changed" behavior docCache alone couldn't deliver (Struct/Func hold
+// live *types.Object/*types.Type from this run's type-checking and
+// can't round-trip through gob, but their rendered stub text can).
+type stubCache struct {
+	Fingerprint string
+	Stub        []byte
+}
+
+// processCache memoizes fully-processed *Package results within a
+// single `gopy bind` run, keyed by the *types.Package pointer identity
+// the type-checker handed us.
+//
+// This is the in-process complement to stubCache's cross-run skip: a
+// Struct/Func graph is built from live *types.Object/*types.Type
+// values tied to one particular type-checking session, so it cannot be
+// serialized to disk and reused by a later `gopy bind` invocation the
+// way the rendered stub bytes can. What processCache instead avoids is
+// the redundant rework within one run: a multi-package bind target
+// commonly reaches the same dependency package through more than one
+// import path in the graph, and every one of those call sites used to
+// pay for a full Package.process of it. Pointer identity is exactly
+// "this is the same types.Package from the same type-checker run", so
+// no separate invalidation logic is needed.
+//
+// Only used when NewPackage is called with no Options: custom Option
+// funcs (idPrefix, include, detectors, docProvider) aren't comparable,
+// so there's no safe way to tell two calls with different opts apart
+// well enough to cache across them.
+var processCache = struct {
+	mu   sync.Mutex
+	pkgs map[*types.Package]*Package
+}{pkgs: map[*types.Package]*Package{}}
+
+func getProcessedPackage(pkg *types.Package) (*Package, bool) {
+	processCache.mu.Lock()
+	defer processCache.mu.Unlock()
+	p, ok := processCache.pkgs[pkg]
+	return p, ok
+}
+
+func putProcessedPackage(pkg *types.Package, p *Package) {
+	processCache.mu.Lock()
+	defer processCache.mu.Unlock()
+	processCache.pkgs[pkg] = p
+}
+
+// cacheDir returns the directory gopy uses to persist per-package doc
+// caches, creating it if necessary. It defaults to
+// $GOPATH/pkg/gopy-cache but can be overridden with GOPY_CACHE_DIR.
+func cacheDir() (string, error) {
+	dir := os.Getenv("GOPY_CACHE_DIR")
+	if dir == "" {
+		gopath := os.Getenv("GOPATH")
+		if gopath == "" {
+			gopath = filepath.Join(os.Getenv("HOME"), "go")
+		}
+		dir = filepath.Join(gopath, "pkg", "gopy-cache")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cachePath returns the on-disk path of importPath's doc cache.
+func cachePath(dir, importPath string) string {
+	sum := sha256.Sum256([]byte(importPath))
+	return filepath.Join(dir, fmt.Sprintf("%x.gob", sum))
+}
+
+// stubCachePath returns the on-disk path of importPath's cached stub,
+// deliberately distinct from cachePath so the doc cache and the stub
+// cache never collide or overwrite one another.
+func stubCachePath(dir, importPath string) string {
+	sum := sha256.Sum256([]byte(importPath))
+	return filepath.Join(dir, fmt.Sprintf("%x.pyi.gob", sum))
+}
+
+// fingerprint computes a stable hash of everything that can change what
+// getDoc or GenPyStub return for pkg: its import path, the name and type
+// string of every exported symbol (for a named type, also its underlying
+// type and method set), every doc string docPkg carries (this *is* a
+// doc cache, so doc-comment-only edits must bust it too), and the
+// active options from opts: the concrete type of the DocProvider and the
+// code address of the include filter and of every extra
+// ProtocolDetector (a cache built under one provider, filter or
+// detector set must not be handed back once it changes, even if the
+// exported API fingerprint alone would still match). Two DocProviders
+// of the same concrete type, or two closures made from the same
+// function literal, hash the same. Unlike a source-file mtime, it is
+// insensitive to reformatting of non-doc code.
+func fingerprint(pkg *types.Package, docPkg *doc.Package, opts *pkgOptions) string {
+	scope := pkg.Scope()
+	names := scope.Names()
+	sort.Strings(names)
+
+	h := sha256.New()
+	io.WriteString(h, pkg.Path())
+	for _, n := range names {
+		obj := scope.Lookup(n)
+		if !obj.Exported() {
+			continue
+		}
+		io.WriteString(h, n)
+		io.WriteString(h, obj.Type().String())
+		if tn, ok := obj.(*types.TypeName); ok {
+			// a named type's type string is just its name: hash its
+			// fields/interface methods and its method set as well.
+			io.WriteString(h, tn.Type().Underlying().String())
+			mset := types.NewMethodSet(types.NewPointer(tn.Type()))
+			for i := 0; i < mset.Len(); i++ {
+				io.WriteString(h, mset.At(i).String())
+			}
+		}
+	}
+
+	hashDocText(h, docPkg)
+
+	fmt.Fprintf(h, "docProvider:%T", opts.docProvider)
+	fmt.Fprintf(h, "include:%x", reflect.ValueOf(opts.include).Pointer())
+	for _, detect := range opts.detectors {
+		fmt.Fprintf(h, "detector:%x", reflect.ValueOf(detect).Pointer())
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// hashDocText feeds every doc string docPkg carries into h, so that a
+// comment-only edit changes the fingerprint even though it leaves
+// every symbol's name and type untouched.
+func hashDocText(h io.Writer, docPkg *doc.Package) {
+	io.WriteString(h, docPkg.Doc)
+
+	for _, c := range docPkg.Consts {
+		io.WriteString(h, c.Doc)
+	}
+	for _, v := range docPkg.Vars {
+		io.WriteString(h, v.Doc)
+	}
+	for _, f := range docPkg.Funcs {
+		io.WriteString(h, f.Doc)
+	}
+	for _, t := range docPkg.Types {
+		io.WriteString(h, t.Doc)
+		for _, m := range t.Methods {
+			io.WriteString(h, m.Doc)
+		}
+		for _, f := range t.Funcs {
+			io.WriteString(h, f.Doc)
+		}
+	}
+}
+
+// loadDocCache reads the persisted doc cache for importPath, returning
+// ok=false if there is none yet, it's corrupt, or it was built for a
+// different fingerprint.
+func loadDocCache(importPath, fp string) (*docCache, bool) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, false
+	}
+	f, err := os.Open(cachePath(dir, importPath))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var c docCache
+	if err := gob.NewDecoder(f).Decode(&c); err != nil {
+		return nil, false
+	}
+	if c.Fingerprint != fp {
+		return nil, false
+	}
+	return &c, true
+}
+
+// saveDocCache persists c for importPath, overwriting any previous
+// cache. Failures to write are non-fatal: the cache is purely an
+// optimization, never a correctness requirement.
+func saveDocCache(importPath string, c *docCache) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	f, err := os.Create(cachePath(dir, importPath))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	gob.NewEncoder(f).Encode(c)
+}
+
+// loadStubCache reads the persisted stub cache for importPath,
+// returning ok=false if there is none yet, it's corrupt, or it was
+// built for a different fingerprint.
+func loadStubCache(importPath, fp string) ([]byte, bool) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, false
+	}
+	f, err := os.Open(stubCachePath(dir, importPath))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var c stubCache
+	if err := gob.NewDecoder(f).Decode(&c); err != nil {
+		return nil, false
+	}
+	if c.Fingerprint != fp {
+		return nil, false
+	}
+	return c.Stub, true
+}
+
+// saveStubCache persists stub for importPath, overwriting any previous
+// cache. Failures to write are non-fatal: the cache is purely an
+// optimization, never a correctness requirement.
+func saveStubCache(importPath, fp string, stub []byte) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	f, err := os.Create(stubCachePath(dir, importPath))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	gob.NewEncoder(f).Encode(&stubCache{Fingerprint: fp, Stub: stub})
+}
@@ -7,6 +7,7 @@ package bind
 import (
 	"fmt"
 	"go/doc"
+	"io"
 	"strings"
 
 	"golang.org/x/tools/go/types"
@@ -24,23 +25,140 @@ type Package struct {
 	vars    []Var
 	structs []Struct
 	funcs   []Func
+
+	ifaces      []Interface
+	namedBasics []NamedBasic
+	namedSlices []NamedSlice
+	namedMaps   []NamedMap
+	namedArrays []NamedArray
+
+	cache *docCache   // memoizes getDoc lookups across gopy invocations
+	opts  *pkgOptions // naming, filtering, doc source and detection behavior
 }
 
-// NewPackage creates a new Package, tying types.Package and ast.Package together.
-func NewPackage(pkg *types.Package, doc *doc.Package) (*Package, error) {
+// NewPackage creates a new Package, tying types.Package and ast.Package
+// together, and runs opts over it. With no opts, NewPackage behaves
+// exactly as it always has: every exported object is bound, ids are
+// prefixed with the Go package name, and no debug dump is produced.
+//
+// With no opts, a repeat call for the same *types.Package (as happens
+// when a dependency is reachable through more than one path in a
+// multi-package bind target) skips Package.process entirely and
+// returns the already-processed Package: see processCache.
+func NewPackage(pkg *types.Package, doc *doc.Package, opts ...Option) (*Package, error) {
+	if len(opts) == 0 {
+		if p, ok := getProcessedPackage(pkg); ok {
+			return p, nil
+		}
+	}
+
+	cfg := defaultOptions(doc)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	fp := fingerprint(pkg, doc, cfg)
+	cache, ok := loadDocCache(pkg.Path(), fp)
+	if !ok {
+		cache = &docCache{Fingerprint: fp, Docs: map[string]string{}}
+	}
+
 	p := &Package{
-		pkg:  pkg,
-		doc:  doc,
-		syms: newSymtab(),
-		objs: map[string]Object{},
+		pkg:   pkg,
+		doc:   doc,
+		syms:  newSymtab(),
+		objs:  map[string]Object{},
+		cache: cache,
+		opts:  cfg,
 	}
 	err := p.process()
 	if err != nil {
 		return nil, err
 	}
+	saveDocCache(pkg.Path(), p.cache)
+
+	if len(opts) == 0 {
+		putProcessedPackage(pkg, p)
+	}
 	return p, err
 }
 
+// Option configures a Package built by NewPackage.
+type Option func(*pkgOptions)
+
+// pkgOptions holds every behavior NewPackage used to bake in: naming,
+// which objects to bind, where doc strings come from, which CPython
+// protocols to detect, and how verbose processing is.
+type pkgOptions struct {
+	idPrefix    func(types.Object) string
+	detectors   []ProtocolDetector
+	docProvider DocProvider
+	include     func(types.Object) bool
+	logger      io.Writer
+}
+
+func defaultOptions(doc *doc.Package) *pkgOptions {
+	return &pkgOptions{
+		idPrefix:    func(o types.Object) string { return o.Pkg().Name() },
+		docProvider: &docPackageProvider{pkg: doc},
+		include:     func(o types.Object) bool { return o.Exported() },
+	}
+}
+
+// WithIDPrefix overrides how NewPackage derives the prefix it glues
+// onto identifiers it hands to the generator (e.g. the "pkgname" in
+// "pkgname_MyStruct_MyMethod"; NewPackage adds the "_" separator
+// itself). The default uses the object's Go package name, matching
+// gopy's historical naming.
+func WithIDPrefix(prefix func(types.Object) string) Option {
+	return func(o *pkgOptions) { o.idPrefix = prefix }
+}
+
+// ProtocolDetector inspects the exported method set of a bind object
+// (keyed by method name) and returns whatever CPython protocol bits it
+// recognizes. See detectProtocols for the built-ins NewPackage always
+// runs; WithProtocolDetectors adds more on top of those.
+type ProtocolDetector func(meths map[string]*types.Selection) Protocol
+
+// WithProtocolDetectors adds extra ProtocolDetector funcs to the
+// built-in ones Package.process runs over every method set, so callers
+// embedding bind as a library can recognize project-specific method
+// shapes as CPython protocols.
+func WithProtocolDetectors(detectors ...ProtocolDetector) Option {
+	return func(o *pkgOptions) { o.detectors = append(o.detectors, detectors...) }
+}
+
+// DocProvider sources the doc string for a types.Object, given the name
+// of its containing scope ("" for package scope). Package.getDoc
+// delegates to one, memoizing the result in its on-disk cache.
+type DocProvider interface {
+	Doc(parent string, o types.Object) string
+}
+
+// WithDocProvider overrides where Package.getDoc sources doc strings
+// from. The default wraps the *doc.Package passed to NewPackage; pass
+// a custom DocProvider to bind packages without full go/doc info, or
+// to pull doc strings from elsewhere (e.g. a sidecar comments file).
+func WithDocProvider(dp DocProvider) Option {
+	return func(o *pkgOptions) { o.docProvider = dp }
+}
+
+// WithIncludeFilter overrides which types.Object a package's scope walk
+// turns into bind objects. The default includes only exported objects;
+// callers that want to expose unexported package-level symbols can
+// widen it. It is consulted for package-scope objects only: struct
+// fields and methods are still bound only when exported.
+func WithIncludeFilter(include func(types.Object) bool) Option {
+	return func(o *pkgOptions) { o.include = include }
+}
+
+// WithLogger makes Package.process dump the resolved symbol table to w
+// for debugging, once processing completes. The default is nil, which
+// disables the dump.
+func WithLogger(w io.Writer) Option {
+	return func(o *pkgOptions) { o.logger = w }
+}
+
 // Name returns the package name.
 func (p *Package) Name() string {
 	return p.pkg.Name()
@@ -49,10 +167,26 @@ func (p *Package) Name() string {
 // getDoc returns the doc string associated with types.Object
 // parent is the name of the containing scope ("" for global scope)
 func (p *Package) getDoc(parent string, o types.Object) string {
+	key := parent + "\x00" + o.Name()
+	if doc, ok := p.cache.Docs[key]; ok {
+		return doc
+	}
+	doc := p.opts.docProvider.Doc(parent, o)
+	p.cache.Docs[key] = doc
+	return doc
+}
+
+// docPackageProvider is the default DocProvider: it walks a *doc.Package
+// the same way gopy always has.
+type docPackageProvider struct {
+	pkg *doc.Package
+}
+
+func (d *docPackageProvider) Doc(parent string, o types.Object) string {
 	n := o.Name()
 	switch o.(type) {
 	case *types.Const:
-		for _, c := range p.doc.Consts {
+		for _, c := range d.pkg.Consts {
 			for _, cn := range c.Names {
 				if n == cn {
 					return c.Doc
@@ -61,7 +195,7 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 		}
 
 	case *types.Var:
-		for _, v := range p.doc.Vars {
+		for _, v := range d.pkg.Vars {
 			for _, vn := range v.Names {
 				if n == vn {
 					return v.Doc
@@ -72,7 +206,7 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 	case *types.Func:
 		doc := func() string {
 			if o.Parent() == nil || (o.Parent() != nil && parent != "") {
-				for _, typ := range p.doc.Types {
+				for _, typ := range d.pkg.Types {
 					if typ.Name != parent {
 						continue
 					}
@@ -91,7 +225,7 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 					}
 				}
 			} else {
-				for _, f := range p.doc.Funcs {
+				for _, f := range d.pkg.Funcs {
 					if n == f.Name {
 						return f.Doc
 					}
@@ -135,7 +269,7 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 		return doc
 
 	case *types.TypeName:
-		for _, t := range p.doc.Types {
+		for _, t := range d.pkg.Types {
 			if n == t.Name {
 				return t.Doc
 			}
@@ -155,11 +289,16 @@ func (p *Package) process() error {
 
 	funcs := make(map[string]Func)
 	structs := make(map[string]Struct)
+	ifaces := make(map[string]Interface)
+	basics := make(map[string]NamedBasic)
+	slices := make(map[string]NamedSlice)
+	maps := make(map[string]NamedMap)
+	arrays := make(map[string]NamedArray)
 
 	scope := p.pkg.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
-		if !obj.Exported() {
+		if !p.opts.include(obj) {
 			continue
 		}
 
@@ -187,6 +326,36 @@ func (p *Package) process() error {
 					return err
 				}
 
+			case *types.Interface:
+				ifaces[name], err = newInterface(p, obj)
+				if err != nil {
+					return err
+				}
+
+			case *types.Basic:
+				basics[name], err = newNamedBasic(p, obj)
+				if err != nil {
+					return err
+				}
+
+			case *types.Slice:
+				slices[name], err = newNamedSlice(p, obj)
+				if err != nil {
+					return err
+				}
+
+			case *types.Map:
+				maps[name], err = newNamedMap(p, obj)
+				if err != nil {
+					return err
+				}
+
+			case *types.Array:
+				arrays[name], err = newNamedArray(p, obj)
+				if err != nil {
+					return err
+				}
+
 			default:
 				//TODO(sbinet)
 				panic(fmt.Errorf("not yet supported: %v (%T)", typ, obj))
@@ -216,34 +385,248 @@ func (p *Package) process() error {
 		}
 
 		ptyp := types.NewPointer(s.GoType())
-		mset := types.NewMethodSet(ptyp)
-		for i := 0; i < mset.Len(); i++ {
-			meth := mset.At(i)
-			if !meth.Obj().Exported() {
-				continue
-			}
-			m, err := newFuncFrom(p, sname, meth.Obj(), meth.Type().(*types.Signature))
-			if err != nil {
-				return err
-			}
-			s.meths = append(s.meths, m)
-			if isStringer(meth.Obj()) {
-				s.prots |= ProtoStringer
-			}
+		meths, prots, err := p.collectMethods(ptyp, sname)
+		if err != nil {
+			return err
 		}
+		s.meths = meths
+		s.prots |= prots
 		p.addStruct(s)
 	}
 
+	// interfaces don't have constructors of their own: a concrete type
+	// satisfying the interface is what gets constructed. only collect
+	// their method set, using the interface type itself (not a pointer
+	// to it, as interfaces are already reference-like).
+	for iname, iface := range ifaces {
+		meths, prots, err := p.collectMethods(iface.GoType(), iname)
+		if err != nil {
+			return err
+		}
+		iface.meths = meths
+		iface.prots |= prots
+		p.addInterface(iface)
+	}
+
+	for bname, b := range basics {
+		for _, fct := range takeCtors(funcs, b.GoType()) {
+			fct.doc = p.getDoc(bname, scope.Lookup(fct.name))
+			fct.ctor = true
+			b.ctors = append(b.ctors, fct)
+		}
+		ptyp := types.NewPointer(b.GoType())
+		meths, prots, err := p.collectMethods(ptyp, bname)
+		if err != nil {
+			return err
+		}
+		b.meths = meths
+		b.prots |= prots
+		p.addNamedBasic(b)
+	}
+
+	for sname, sl := range slices {
+		for _, fct := range takeCtors(funcs, sl.GoType()) {
+			fct.doc = p.getDoc(sname, scope.Lookup(fct.name))
+			fct.ctor = true
+			sl.ctors = append(sl.ctors, fct)
+		}
+		ptyp := types.NewPointer(sl.GoType())
+		meths, prots, err := p.collectMethods(ptyp, sname)
+		if err != nil {
+			return err
+		}
+		sl.meths = meths
+		sl.prots |= prots | ProtoSequence
+		p.addNamedSlice(sl)
+	}
+
+	for mname, m := range maps {
+		for _, fct := range takeCtors(funcs, m.GoType()) {
+			fct.doc = p.getDoc(mname, scope.Lookup(fct.name))
+			fct.ctor = true
+			m.ctors = append(m.ctors, fct)
+		}
+		ptyp := types.NewPointer(m.GoType())
+		meths, prots, err := p.collectMethods(ptyp, mname)
+		if err != nil {
+			return err
+		}
+		m.meths = meths
+		m.prots |= prots | ProtoMapping
+		p.addNamedMap(m)
+	}
+
+	for aname, a := range arrays {
+		for _, fct := range takeCtors(funcs, a.GoType()) {
+			fct.doc = p.getDoc(aname, scope.Lookup(fct.name))
+			fct.ctor = true
+			a.ctors = append(a.ctors, fct)
+		}
+		ptyp := types.NewPointer(a.GoType())
+		meths, prots, err := p.collectMethods(ptyp, aname)
+		if err != nil {
+			return err
+		}
+		a.meths = meths
+		a.prots |= prots | ProtoSequence
+		p.addNamedArray(a)
+	}
+
 	for _, fct := range funcs {
 		p.addFunc(fct)
 	}
 
-	for n, sym := range p.syms.syms {
-		fmt.Printf("--> [%s]: %#v\n", n, sym)
+	if p.opts.logger != nil {
+		for n, sym := range p.syms.syms {
+			fmt.Fprintf(p.opts.logger, "--> [%s]: %#v\n", n, sym)
+		}
 	}
 	return err
 }
 
+// collectMethods walks the method set of typ (usually *T for some named
+// type T) and turns every exported method into a Func, also ORing in
+// every CPython protocol the method set satisfies.
+func (p *Package) collectMethods(typ types.Type, parent string) ([]Func, Protocol, error) {
+	var (
+		meths  []Func
+		prots  Protocol
+		byName = map[string]*types.Selection{}
+	)
+	mset := types.NewMethodSet(typ)
+	for i := 0; i < mset.Len(); i++ {
+		meth := mset.At(i)
+		if !meth.Obj().Exported() {
+			continue
+		}
+		m, err := newFuncFrom(p, parent, meth.Obj(), meth.Type().(*types.Signature))
+		if err != nil {
+			return nil, 0, err
+		}
+		meths = append(meths, m)
+		byName[meth.Obj().Name()] = meth
+		if isStringer(meth.Obj()) {
+			prots |= ProtoStringer
+		}
+	}
+	prots |= detectProtocols(byName)
+	for _, detect := range p.opts.detectors {
+		prots |= detect(byName)
+	}
+	return meths, prots, nil
+}
+
+// detectProtocols inspects the named, exported methods of a bind object
+// and maps well-known Go method shapes onto the CPython dunder
+// protocols they make available. The resulting bits are read by
+// writeProtocolStubs in genpyi.go (see Protocol).
+func detectProtocols(byName map[string]*types.Selection) Protocol {
+	var prots Protocol
+
+	// Len() int -> sq_length / mp_length.
+	if sig, ok := methodSig(byName, "Len", 0, 1); ok {
+		if isBasicKind(sig.Results().At(0).Type(), types.IsInteger) {
+			prots |= ProtoLen
+		}
+	}
+
+	// Get(k)/Set(k, v)/Del(k) -> the mapping protocol.
+	if _, ok := methodSig(byName, "Get", 1, -1); ok {
+		prots |= ProtoMapping
+	}
+	if _, ok := methodSig(byName, "Set", 2, -1); ok {
+		prots |= ProtoMapping
+	}
+	if _, ok := methodSig(byName, "Del", 1, -1); ok {
+		prots |= ProtoMapping
+	}
+
+	// Next() (T, bool) -> tp_iter/tp_iternext. Channel-returning methods
+	// are not detected.
+	if sig, ok := methodSig(byName, "Next", 0, 2); ok {
+		if isBasicKind(sig.Results().At(1).Type(), types.IsBoolean) {
+			prots |= ProtoIter
+		}
+	}
+
+	// Equal(other) bool / Less(other) bool -> tp_richcompare.
+	if sig, ok := methodSig(byName, "Equal", 1, 1); ok {
+		if isBasicKind(sig.Results().At(0).Type(), types.IsBoolean) {
+			prots |= ProtoCompare
+		}
+	}
+	if sig, ok := methodSig(byName, "Less", 1, 1); ok {
+		if isBasicKind(sig.Results().At(0).Type(), types.IsBoolean) {
+			prots |= ProtoCompare
+		}
+	}
+
+	// Hash() uint64 -> tp_hash.
+	if sig, ok := methodSig(byName, "Hash", 0, 1); ok {
+		if isBasicKind(sig.Results().At(0).Type(), types.IsInteger) {
+			prots |= ProtoHash
+		}
+	}
+
+	// Add/Sub/Mul/Div on the receiver type -> nb_add, nb_subtract, ...
+	for _, name := range [...]string{"Add", "Sub", "Mul", "Div"} {
+		if _, ok := methodSig(byName, name, 1, 1); ok {
+			prots |= ProtoNumber
+		}
+	}
+
+	return prots
+}
+
+// methodSig looks up name in byName and, if found, returns its
+// signature along with whether it has exactly nIn parameters and nOut
+// results (-1 means "don't care").
+func methodSig(byName map[string]*types.Selection, name string, nIn, nOut int) (*types.Signature, bool) {
+	sel, ok := byName[name]
+	if !ok {
+		return nil, false
+	}
+	sig, ok := sel.Type().(*types.Signature)
+	if !ok {
+		return nil, false
+	}
+	if nIn >= 0 && sig.Params().Len() != nIn {
+		return nil, false
+	}
+	if nOut >= 0 && sig.Results().Len() != nOut {
+		return nil, false
+	}
+	return sig, true
+}
+
+// isBasicKind reports whether typ's underlying type is a predeclared
+// basic type whose BasicInfo includes info (e.g. types.IsInteger).
+func isBasicKind(typ types.Type, info types.BasicInfo) bool {
+	b, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	return b.Info()&info != 0
+}
+
+// takeCtors pops every remaining free function whose return type
+// matches typ, the same way the struct loop above does inline, so
+// named basic/slice/map/array types don't silently lose all but the
+// first of several constructor-shaped functions back into funcs.
+func takeCtors(funcs map[string]Func, typ types.Type) []Func {
+	var ctors []Func
+	for name, fct := range funcs {
+		if fct.Return() == nil {
+			continue
+		}
+		if fct.Return() == typ {
+			delete(funcs, name)
+			ctors = append(ctors, fct)
+		}
+	}
+	return ctors
+}
+
 func (p *Package) addConst(obj *types.Const) {
 	p.consts = append(p.consts, newConst(p, obj))
 }
@@ -262,17 +645,60 @@ func (p *Package) addFunc(f Func) {
 	p.objs[f.GoName()] = f
 }
 
+func (p *Package) addInterface(i Interface) {
+	p.ifaces = append(p.ifaces, i)
+	p.objs[i.GoName()] = i
+}
+
+func (p *Package) addNamedBasic(b NamedBasic) {
+	p.namedBasics = append(p.namedBasics, b)
+	p.objs[b.GoName()] = b
+}
+
+func (p *Package) addNamedSlice(s NamedSlice) {
+	p.namedSlices = append(p.namedSlices, s)
+	p.objs[s.GoName()] = s
+}
+
+func (p *Package) addNamedMap(m NamedMap) {
+	p.namedMaps = append(p.namedMaps, m)
+	p.objs[m.GoName()] = m
+}
+
+func (p *Package) addNamedArray(a NamedArray) {
+	p.namedArrays = append(p.namedArrays, a)
+	p.objs[a.GoName()] = a
+}
+
 // Lookup returns the bind.Object corresponding to a types.Object
 func (p *Package) Lookup(o types.Object) (Object, bool) {
 	obj, ok := p.objs[o.Name()]
 	return obj, ok
 }
 
-// Protocol encodes the various protocols a python type may implement
+// Protocol encodes the various protocols a python type may implement.
+//
+// detectProtocols and collectMethods OR the bits a type qualifies for
+// into Struct.prots/Interface.prots/NamedBasic.prots/etc. Today the
+// only thing in this tree that reads those bits back out is
+// genpyi.go's writeProtocolStubs, which emits the matching dunder
+// methods (__len__, __getitem__, __iter__, ...) into the generated
+// .pyi so mypy/IDEs see the right surface. The comments below name the
+// CPython C-level slot each protocol corresponds to for when a C
+// codegen pass exists to wire them up; no such pass is in this tree
+// yet, so prots does not yet change anything about the generated C
+// extension itself.
 type Protocol int
 
 const (
 	ProtoStringer Protocol = 1 << iota
+	ProtoSequence // implements the Python sequence protocol (sq_* slots)
+	ProtoMapping  // implements the Python mapping protocol (mp_* slots)
+	ProtoLen      // Len() int -> sq_length / mp_length
+	ProtoIter     // Next() (T, bool) -> tp_iter / tp_iternext
+	ProtoCompare  // Equal/Less -> tp_richcompare
+	ProtoHash     // Hash() uint64 -> tp_hash
+	ProtoNumber   // Add/Sub/Mul/Div -> nb_add, nb_subtract, nb_multiply, nb_true_divide
 )
 
 // Struct collects informations about a go struct.
@@ -292,7 +718,7 @@ func newStruct(p *Package, obj *types.TypeName) (Struct, error) {
 	s := Struct{
 		pkg: p,
 		obj: obj,
-		id:  obj.Pkg().Name() + "_" + obj.Name(),
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
 		doc: p.getDoc("", obj),
 	}
 	return s, nil
@@ -322,6 +748,265 @@ func (s Struct) Struct() *types.Struct {
 	return s.obj.Type().Underlying().(*types.Struct)
 }
 
+// Interface collects informations about a go interface.
+//
+// This models the bind-side view of an interface (its method set,
+// metadata and detected protocols); it does not generate a C
+// extension. Unlike a Struct, an Interface has no constructor of its
+// own: at runtime a Python Interface instance would need to wrap
+// whatever concrete Go value was handed back across the cgo boundary,
+// with the generated wrapper storing an unsafe.Pointer to that value
+// together with its itab (or a lookup key into a concrete-type
+// registry populated at bind-generation time) so calls dispatch to the
+// right concrete method and the value can be handed back to Go
+// unchanged. That C/Python wrapper codegen is follow-up work requiring
+// a C emitter this tree doesn't have. What genpyi.go's
+// writeProtocolStubs does have, today, is the .pyi-level equivalent
+// for NamedSlice/NamedMap's sequence/mapping protocols: a NamedMap
+// with ProtoMapping set gets __getitem__/__setitem__/__delitem__ stubs
+// generated from its prots, so the Python-visible surface mypy and
+// IDEs see already reflects it, even though the underlying C slots
+// those dunders would call into aren't wired up anywhere yet.
+type Interface struct {
+	pkg *Package
+	obj *types.TypeName
+
+	id    string
+	doc   string
+	meths []Func
+
+	prots Protocol
+}
+
+func newInterface(p *Package, obj *types.TypeName) (Interface, error) {
+	i := Interface{
+		pkg: p,
+		obj: obj,
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
+		doc: p.getDoc("", obj),
+	}
+	return i, nil
+}
+
+func (i Interface) Package() *Package {
+	return i.pkg
+}
+
+func (i Interface) ID() string {
+	return i.id
+}
+
+func (i Interface) Doc() string {
+	return i.doc
+}
+
+func (i Interface) GoType() types.Type {
+	return i.obj.Type()
+}
+
+func (i Interface) GoName() string {
+	return i.obj.Name()
+}
+
+func (i Interface) Interface() *types.Interface {
+	return i.obj.Type().Underlying().(*types.Interface)
+}
+
+// NamedBasic collects informations about a named type whose underlying
+// type is one of the predeclared basic types (e.g. `type Duration int64`).
+type NamedBasic struct {
+	pkg *Package
+	obj *types.TypeName
+
+	id    string
+	doc   string
+	ctors []Func
+	meths []Func
+
+	prots Protocol
+}
+
+func newNamedBasic(p *Package, obj *types.TypeName) (NamedBasic, error) {
+	b := NamedBasic{
+		pkg: p,
+		obj: obj,
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
+		doc: p.getDoc("", obj),
+	}
+	return b, nil
+}
+
+func (b NamedBasic) Package() *Package {
+	return b.pkg
+}
+
+func (b NamedBasic) ID() string {
+	return b.id
+}
+
+func (b NamedBasic) Doc() string {
+	return b.doc
+}
+
+func (b NamedBasic) GoType() types.Type {
+	return b.obj.Type()
+}
+
+func (b NamedBasic) GoName() string {
+	return b.obj.Name()
+}
+
+func (b NamedBasic) Basic() *types.Basic {
+	return b.obj.Type().Underlying().(*types.Basic)
+}
+
+// NamedSlice collects informations about a named type whose underlying
+// type is a slice (e.g. `type StringList []string`). It is bound as a
+// Python object implementing the sequence protocol.
+type NamedSlice struct {
+	pkg *Package
+	obj *types.TypeName
+
+	id    string
+	doc   string
+	ctors []Func
+	meths []Func
+
+	prots Protocol
+}
+
+func newNamedSlice(p *Package, obj *types.TypeName) (NamedSlice, error) {
+	s := NamedSlice{
+		pkg: p,
+		obj: obj,
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
+		doc: p.getDoc("", obj),
+	}
+	return s, nil
+}
+
+func (s NamedSlice) Package() *Package {
+	return s.pkg
+}
+
+func (s NamedSlice) ID() string {
+	return s.id
+}
+
+func (s NamedSlice) Doc() string {
+	return s.doc
+}
+
+func (s NamedSlice) GoType() types.Type {
+	return s.obj.Type()
+}
+
+func (s NamedSlice) GoName() string {
+	return s.obj.Name()
+}
+
+func (s NamedSlice) Slice() *types.Slice {
+	return s.obj.Type().Underlying().(*types.Slice)
+}
+
+// NamedMap collects informations about a named type whose underlying
+// type is a map (e.g. `type Headers map[string]string`). It is bound as
+// a Python object implementing the mapping protocol.
+type NamedMap struct {
+	pkg *Package
+	obj *types.TypeName
+
+	id    string
+	doc   string
+	ctors []Func
+	meths []Func
+
+	prots Protocol
+}
+
+func newNamedMap(p *Package, obj *types.TypeName) (NamedMap, error) {
+	m := NamedMap{
+		pkg: p,
+		obj: obj,
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
+		doc: p.getDoc("", obj),
+	}
+	return m, nil
+}
+
+func (m NamedMap) Package() *Package {
+	return m.pkg
+}
+
+func (m NamedMap) ID() string {
+	return m.id
+}
+
+func (m NamedMap) Doc() string {
+	return m.doc
+}
+
+func (m NamedMap) GoType() types.Type {
+	return m.obj.Type()
+}
+
+func (m NamedMap) GoName() string {
+	return m.obj.Name()
+}
+
+func (m NamedMap) Map() *types.Map {
+	return m.obj.Type().Underlying().(*types.Map)
+}
+
+// NamedArray collects informations about a named type whose underlying
+// type is a fixed-size array (e.g. `type Vec3 [3]float64`). It is bound
+// as a Python object implementing the sequence protocol.
+type NamedArray struct {
+	pkg *Package
+	obj *types.TypeName
+
+	id    string
+	doc   string
+	ctors []Func
+	meths []Func
+
+	prots Protocol
+}
+
+func newNamedArray(p *Package, obj *types.TypeName) (NamedArray, error) {
+	a := NamedArray{
+		pkg: p,
+		obj: obj,
+		id:  p.opts.idPrefix(obj) + "_" + obj.Name(),
+		doc: p.getDoc("", obj),
+	}
+	return a, nil
+}
+
+func (a NamedArray) Package() *Package {
+	return a.pkg
+}
+
+func (a NamedArray) ID() string {
+	return a.id
+}
+
+func (a NamedArray) Doc() string {
+	return a.doc
+}
+
+func (a NamedArray) GoType() types.Type {
+	return a.obj.Type()
+}
+
+func (a NamedArray) GoName() string {
+	return a.obj.Name()
+}
+
+func (a NamedArray) Array() *types.Array {
+	return a.obj.Type().Underlying().(*types.Array)
+}
+
 // A Signature represents a (non-builtin) function or method type.
 type Signature struct {
 	ret  []*Var
@@ -371,43 +1056,29 @@ type Func struct {
 
 	id   string
 	doc  string
-	ret  types.Type // return type, if any
-	err  bool       // true if original go func has comma-error
-	ctor bool       // true if this is a newXXX function
+	rets []types.Type // go return types, in declaration order (a trailing error is kept, not stripped)
+	ctor bool          // true if this is a newXXX function
 }
 
 func newFuncFrom(p *Package, parent string, obj types.Object, sig *types.Signature) (Func, error) {
-	haserr := false
 	res := sig.Results()
-	var ret types.Type
+	rets := make([]types.Type, res.Len())
+	for i := 0; i < res.Len(); i++ {
+		rets[i] = res.At(i).Type()
+	}
 
-	switch res.Len() {
-	case 2:
-		if !isErrorType(res.At(1).Type()) {
+	for i, ret := range rets {
+		if isErrorType(ret) && i != len(rets)-1 {
 			return Func{}, fmt.Errorf(
-				"bind: second result value must be of type error: %s",
+				"bind: only the last result value may be of type error: %s",
 				obj,
 			)
 		}
-		haserr = true
-		ret = res.At(0).Type()
-
-	case 1:
-		if isErrorType(res.At(0).Type()) {
-			haserr = true
-			ret = nil
-		} else {
-			ret = res.At(0).Type()
-		}
-	case 0:
-		ret = nil
-	default:
-		return Func{}, fmt.Errorf("bind: too many results to return: %v", obj)
 	}
 
-	id := obj.Pkg().Name() + "_" + obj.Name()
+	id := p.opts.idPrefix(obj) + "_" + obj.Name()
 	if parent != "" {
-		id = obj.Pkg().Name() + "_" + parent + "_" + obj.Name()
+		id = p.opts.idPrefix(obj) + "_" + parent + "_" + obj.Name()
 	}
 
 	return Func{
@@ -417,8 +1088,7 @@ func newFuncFrom(p *Package, parent string, obj types.Object, sig *types.Signatu
 		name: obj.Name(),
 		id:   id,
 		doc:  p.getDoc(parent, obj),
-		ret:  ret,
-		err:  haserr,
+		rets: rets,
 	}, nil
 }
 
@@ -446,8 +1116,34 @@ func (f Func) Signature() *Signature {
 	return f.sig
 }
 
+// Err reports whether f's last return value is a Go error, i.e. whether
+// the original Go func/method has the idiomatic trailing comma-error.
+func (f Func) Err() bool {
+	if len(f.rets) == 0 {
+		return false
+	}
+	return isErrorType(f.rets[len(f.rets)-1])
+}
+
+// Returns returns the non-error Go result types of f, in declaration
+// order. The trailing error result, if any, is not included: use Err
+// to find out whether f can also fail.
+func (f Func) Returns() []types.Type {
+	if f.Err() {
+		return f.rets[:len(f.rets)-1]
+	}
+	return f.rets
+}
+
+// Return is a shim for callers that only expect a single return value:
+// it returns the first non-error result type, or nil if f returns
+// nothing of interest. New code should use Returns instead.
 func (f Func) Return() types.Type {
-	return f.ret
+	rets := f.Returns()
+	if len(rets) == 0 {
+		return nil
+	}
+	return rets[0]
 }
 
 type Const struct {
@@ -459,8 +1155,7 @@ type Const struct {
 }
 
 func newConst(p *Package, o *types.Const) Const {
-	pkg := o.Pkg()
-	id := pkg.Name() + "_" + o.Name()
+	id := p.opts.idPrefix(o) + "_" + o.Name()
 	doc := p.getDoc("", o)
 
 	res := []*Var{newVar(p, o.Type(), "ret", o.Name(), doc)}
@@ -472,8 +1167,7 @@ func newConst(p *Package, o *types.Const) Const {
 		name: o.Name(),
 		id:   "get_" + id,
 		doc:  doc,
-		ret:  o.Type(),
-		err:  false,
+		rets: []types.Type{o.Type()},
 	}
 
 	return Const{